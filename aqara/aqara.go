@@ -2,9 +2,11 @@ package aqara
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -17,6 +19,9 @@ import (
 
 const nonceLength int = 16
 
+// pageSize is the number of entries requested per page from paginated intents.
+const pageSize int = 100
+
 type AqaraRegionServer string
 
 const (
@@ -41,6 +46,20 @@ type AqaraResponse struct {
 	Result        json.RawMessage `json:"result"`
 }
 
+// APIError is returned when the Aqara API answers with a non-zero result code.
+type APIError struct {
+	Code          int
+	Message       string
+	MessageDetail string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("request against Aqara API failed with code: %v", e.Code)
+}
+
+// ErrNotAuthenticated is returned by Validate when no access token was obtained yet.
+var ErrNotAuthenticated = errors.New("no access token available, login required")
+
 type AqaraClient struct {
 	region       AqaraRegionServer
 	appID        string
@@ -50,6 +69,10 @@ type AqaraClient struct {
 	accessToken  string
 	refreshToken string
 	debug        bool
+	httpClient   *http.Client
+	throttling   throttleState
+	events       *EventBus
+	pollInterval time.Duration
 }
 
 // New returns a new AqaraClient.
@@ -63,9 +86,17 @@ func New(region AqaraRegionServer, appID, keyID, appKey, account string, debug b
 		accessToken:  "", // updated after login
 		refreshToken: "", // updated after login
 		debug:        debug,
+		httpClient:   &http.Client{},
+		events:       NewEventBus(),
+		pollInterval: defaultPollInterval,
 	}
 }
 
+// SetHTTPClient replaces the HTTP client used for all calls against the Aqara API.
+func (a *AqaraClient) SetHTTPClient(httpClient *http.Client) {
+	a.httpClient = httpClient
+}
+
 // GetAuthCode will request a new authorization code for a given Aqara account.
 func (a *AqaraClient) GetAuthCode() {
 	type Data struct {
@@ -85,7 +116,7 @@ func (a *AqaraClient) GetAuthCode() {
 
 	response := AqaraResponse{}
 
-	if err := a.apiCall(request, &response, false); err != nil {
+	if err := a.apiCall(context.Background(), request, &response, false); err != nil {
 		log.Printf("Failed to do auth request: %v", err)
 	}
 }
@@ -109,7 +140,7 @@ func (a *AqaraClient) GetToken(authCode string) {
 
 	response := AqaraResponse{}
 
-	if err := a.apiCall(request, &response, false); err != nil {
+	if err := a.apiCall(context.Background(), request, &response, false); err != nil {
 		log.Printf("Failed to do token request: %v", err)
 	}
 
@@ -133,8 +164,38 @@ func (a *AqaraClient) GetToken(authCode string) {
 	}
 }
 
+type Device struct {
+	DID             string `json:"did"`
+	ParentDID       string `json:"parentDid"`
+	PositionID      string `json:"positionId"`
+	CreateTime      string `json:"createTime"`
+	UpdateTime      string `json:"updateTime"`
+	Model           string `json:"model"`
+	ModelType       int    `json:"modelType"`
+	State           int    `json:"state"`
+	FirmwareVersion string `json:"firmwareVersion"`
+	DeviceName      string `json:"deviceName"`
+	TimeZone        string `json:"timeZone"`
+}
+
 // GetDevices retreives all devices for a certain account.
 func (a *AqaraClient) GetDevices() {
+	devices, err := a.QueryDevices(context.Background())
+	if err != nil {
+		log.Printf("Failed query devices: %v", err)
+		return
+	}
+
+	log.Printf("Number of devices received: %v", len(devices))
+	for _, device := range devices {
+		fmt.Printf("Device Name:  %v", device.DeviceName)
+		fmt.Printf("Device Model: %v", device.Model)
+	}
+}
+
+// QueryDevices returns the devices with the given device IDs.
+// If no device IDs are provided, all devices of the account are returned.
+func (a *AqaraClient) QueryDevices(ctx context.Context, dids ...string) ([]Device, error) {
 	type Data struct {
 		DeviceIDs  []string `json:"dids"`
 		PositionID string   `json:"positionId"`
@@ -142,58 +203,81 @@ func (a *AqaraClient) GetDevices() {
 		PageSize   int      `json:"pageSize"`
 	}
 
-	request := AqaraRequest{
-		Intent: "query.device.info",
-		Data: Data{
-			DeviceIDs:  []string{},
-			PositionID: "",
-			PageNum:    1,
-			PageSize:   100,
-		},
+	type Result struct {
+		Data       []Device `json:"data"`
+		TotalCount int      `json:"totalCount"`
 	}
 
-	response := AqaraResponse{}
-
-	if err := a.apiCall(request, &response, true); err != nil {
-		log.Printf("Failed query devices: %v", err)
+	if dids == nil {
+		dids = []string{}
 	}
 
-	if response.Code == 0 {
-		type Device struct {
-			DID             string `json:"did"`
-			ParentDID       string `json:"parentDid"`
-			PositionID      string `json:"positionId"`
-			CreateTime      string `json:"createTime"`
-			UpdateTime      string `json:"updateTime"`
-			Model           string `json:"model"`
-			ModelType       int    `json:"modelType"`
-			State           int    `json:"state"`
-			FirmwareVersion string `json:"firmwareVersion"`
-			DeviceName      string `json:"deviceName"`
-			TimeZone        string `json:"timeZone"`
+	var devices []Device
+	for pageNum := 1; ; pageNum++ {
+		request := AqaraRequest{
+			Intent: "query.device.info",
+			Data: Data{
+				DeviceIDs:  dids,
+				PositionID: "",
+				PageNum:    pageNum,
+				PageSize:   pageSize,
+			},
 		}
 
-		type Result struct {
-			Data       []Device `json:"data"`
-			TotalCount int      `json:"totalCount"`
+		response := AqaraResponse{}
+
+		if err := a.apiCall(ctx, request, &response, true); err != nil {
+			return nil, err
 		}
 
 		var result Result
 		if err := json.Unmarshal(response.Result, &result); err != nil {
-			log.Printf("Failed to unmarshal result: %v", err)
+			return nil, fmt.Errorf("failed to unmarshal result: %w", err)
 		}
 
-		log.Printf("Number of devices received: %v", result.TotalCount)
-		for _, device := range result.Data {
-			fmt.Printf("Device Name:  %v", device.DeviceName)
-			fmt.Printf("Device Model: %v", device.Model)
+		devices = append(devices, result.Data...)
+		if len(result.Data) == 0 || len(devices) >= result.TotalCount {
+			return devices, nil
 		}
 	}
 }
 
+// Validate performs a minimal authenticated call against the Aqara API and
+// returns nil if credentials, access token and region are currently valid.
+// It is cheap enough to be used as a health or readiness check; ctx bounds the call.
+func (a *AqaraClient) Validate(ctx context.Context) error {
+	if a.accessToken == "" {
+		return ErrNotAuthenticated
+	}
+
+	type Data struct {
+		ParentPositionID string `json:"parentPositionId"`
+		PageNum          int    `json:"pageNum"`
+		PageSize         int    `json:"pageSize"`
+	}
+
+	request := AqaraRequest{
+		Intent: "query.position.info",
+		Data: Data{
+			ParentPositionID: "",
+			PageNum:          1,
+			PageSize:         1,
+		},
+	}
+
+	response := AqaraResponse{}
+
+	if err := a.apiCall(ctx, request, &response, true); err != nil {
+		return fmt.Errorf("failed to validate connection to %v: %w", a.region, err)
+	}
+
+	return nil
+}
+
 // apiCall sends request to the Aqara API with the provided AqaraRequest (intent).
-// Response is updated in the provided AqaraResponse pointer.
-func (a *AqaraClient) apiCall(aqaraRequest AqaraRequest, aqaraResponse *AqaraResponse, authenticated bool) error {
+// Response is updated in the provided AqaraResponse pointer. The call, including
+// any throttling cool-down, is aborted once ctx is done.
+func (a *AqaraClient) apiCall(ctx context.Context, aqaraRequest AqaraRequest, aqaraResponse *AqaraResponse, authenticated bool) error {
 
 	const apiEndpoint = "/v3.0/open/api"
 	url := fmt.Sprintf("https://%s%s", a.region, apiEndpoint)
@@ -204,12 +288,16 @@ func (a *AqaraClient) apiCall(aqaraRequest AqaraRequest, aqaraResponse *AqaraRes
 		return err
 	}
 
-	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(requestBody))
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(requestBody))
 	if err != nil {
 		log.Printf("Failed to prepare request: %v", err)
 		return err
 	}
 
+	if err := a.waitForThrottle(ctx); err != nil {
+		return err
+	}
+
 	nonce := getNonce(nonceLength)
 	timestamp := getTimestamp()
 	var signature string
@@ -228,17 +316,21 @@ func (a *AqaraClient) apiCall(aqaraRequest AqaraRequest, aqaraResponse *AqaraRes
 	request.Header.Add("Sign", signature)
 	request.Header.Add("Lang", "en")
 
-	client := &http.Client{}
-	response, err := client.Do(request)
+	response, err := a.httpClient.Do(request)
 	if err != nil {
 		log.Printf("Failed to do request: %v", err)
 		return err
 	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusTooManyRequests {
+		log.Printf("Status %v received", response.StatusCode)
+		return a.throttle(response.StatusCode, 0, response.Header.Get("Retry-After"))
+	}
 
 	if response.StatusCode == http.StatusOK {
 		log.Printf("Call to %q successful", url)
 		responseBody, err := io.ReadAll(response.Body)
-		defer response.Body.Close()
 		if err != nil {
 			log.Printf("Failed to get response body: %v", err)
 			return err
@@ -252,13 +344,22 @@ func (a *AqaraClient) apiCall(aqaraRequest AqaraRequest, aqaraResponse *AqaraRes
 
 		if aqaraResponse.Code != 0 {
 			log.Printf("Aqara response with code %v received with message %v", aqaraResponse.Code, aqaraResponse.MessageDetail)
-			return fmt.Errorf("request against Aqara API failed with code: %v", aqaraResponse.Code)
+			if aqaraResponse.Code == codeTooManyRequests {
+				return a.throttle(response.StatusCode, aqaraResponse.Code, response.Header.Get("Retry-After"))
+			}
+			return &APIError{
+				Code:          aqaraResponse.Code,
+				Message:       aqaraResponse.Message,
+				MessageDetail: aqaraResponse.MessageDetail,
+			}
 		}
 
 		if a.debug {
 			log.Printf("**DEBUG**: %v", string(responseBody))
 		}
 
+		a.resetThrottle()
+
 		return nil
 	} else {
 		log.Printf("Status %v received", response.StatusCode)
@@ -1,7 +1,15 @@
 package aqara
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 )
 
 func TestSign(t *testing.T) {
@@ -17,10 +25,141 @@ func TestSign(t *testing.T) {
 
 	expectedSignature := "314a6f6fd46264e6ec872e21f88361c3"
 
-	aqaraClient := New(ServerRegionEurope, appID, appKey, keyID, account, false)
+	aqaraClient := New(ServerRegionEurope, appID, keyID, appKey, account, false)
 	signature := aqaraClient.sign(accessToken, nonce, timestamp)
 
 	if signature != expectedSignature {
 		t.Errorf("got signature %q, wanted signature %q", signature, expectedSignature)
 	}
 }
+
+// rewriteTransport redirects all requests to a local test server.
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (rt rewriteTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r.URL.Scheme = rt.target.Scheme
+	r.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// newTestClient returns a client whose API calls are answered by handler.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *AqaraClient {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse test server url: %v", err)
+	}
+
+	aqaraClient := New(ServerRegionEurope, "appid", "keyid", "appkey", "account", false)
+	aqaraClient.SetHTTPClient(&http.Client{Transport: rewriteTransport{target: target}})
+
+	return aqaraClient
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		accessToken string
+		response    string
+		wantErr     bool
+		wantCode    int
+	}{
+		{"valid", "token", `{"code":0,"result":{"data":[],"totalCount":0}}`, false, 0},
+		{"invalid token", "token", `{"code":108,"message":"Token is invalid"}`, true, 108},
+		{"no token", "", `{"code":0}`, true, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			aqaraClient := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Accesstoken") != test.accessToken {
+					t.Errorf("got access token %q, wanted %q", r.Header.Get("Accesstoken"), test.accessToken)
+				}
+				var request struct {
+					Intent string                     `json:"intent"`
+					Data   map[string]json.RawMessage `json:"data"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+					return
+				}
+				if _, ok := request.Data["parentPositionId"]; request.Intent != "query.position.info" || !ok {
+					t.Errorf("got intent %q with data %v, wanted query.position.info with parentPositionId", request.Intent, request.Data)
+				}
+				fmt.Fprint(w, test.response)
+			})
+			aqaraClient.accessToken = test.accessToken
+
+			err := aqaraClient.Validate(context.Background())
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, wanted error: %v", err, test.wantErr)
+			}
+
+			if test.wantCode != 0 {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("got error %v, wanted APIError", err)
+				}
+				if apiErr.Code != test.wantCode {
+					t.Errorf("got code %v, wanted code %v", apiErr.Code, test.wantCode)
+				}
+			}
+			if test.accessToken == "" && !errors.Is(err, ErrNotAuthenticated) {
+				t.Errorf("got error %v, wanted %v", err, ErrNotAuthenticated)
+			}
+		})
+	}
+}
+
+func TestValidateContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	aqaraClient := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	aqaraClient.accessToken = "token"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := aqaraClient.Validate(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, wanted %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestQueryDevices(t *testing.T) {
+	aqaraClient := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Intent string `json:"intent"`
+			Data   struct {
+				DeviceIDs []string `json:"dids"`
+			} `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			return
+		}
+		if request.Intent != "query.device.info" || len(request.Data.DeviceIDs) != 1 || request.Data.DeviceIDs[0] != "lumi.1" {
+			t.Errorf("got intent %q for devices %v", request.Intent, request.Data.DeviceIDs)
+		}
+		fmt.Fprint(w, `{"code":0,"result":{"data":[{"did":"lumi.1","deviceName":"Plug","model":"lumi.plug.v1"}],"totalCount":1}}`)
+	})
+
+	devices, err := aqaraClient.QueryDevices(context.Background(), "lumi.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(devices) != 1 || devices[0].DID != "lumi.1" || devices[0].Model != "lumi.plug.v1" {
+		t.Errorf("got devices %+v", devices)
+	}
+}
@@ -0,0 +1,141 @@
+package aqara
+
+import (
+	"context"
+	"sync"
+)
+
+// Event is a single resource report as pushed by the Aqara message service.
+type Event struct {
+	SubjectID  string `json:"subjectId"`
+	ResourceID string `json:"resourceId"`
+	Value      string `json:"value"`
+	Time       string `json:"time"`
+}
+
+// EventBus fans out resource events to all current subscribers.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers map[int]*subscriber
+	nextID      int
+}
+
+type subscriber struct {
+	ch   chan Event
+	done chan struct{}
+
+	// mu guards ch against being closed while an event is sent to it.
+	mu     sync.RWMutex
+	closed bool
+}
+
+// send delivers event to the subscriber. If wait is false, the event is
+// dropped when the subscriber's buffer is full.
+func (s *subscriber) send(ctx context.Context, event Event, wait bool) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil
+	}
+
+	if !wait {
+		select {
+		case s.ch <- event:
+		default:
+		}
+		return nil
+	}
+
+	select {
+	case s.ch <- event:
+	case <-s.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+// NewEventBus returns a new EventBus without any subscribers.
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[int]*subscriber),
+	}
+}
+
+// Subscribe registers a new subscriber with the given channel buffer size.
+// The returned function removes the subscription and closes the channel.
+func (b *EventBus) Subscribe(buffer int) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	sub := &subscriber{
+		ch:   make(chan Event, buffer),
+		done: make(chan struct{}),
+	}
+	b.subscribers[id] = sub
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			// Release a PublishWait blocked on this subscriber before
+			// waiting for it to finish sending.
+			close(sub.done)
+
+			b.mu.Lock()
+			delete(b.subscribers, id)
+			b.mu.Unlock()
+
+			sub.mu.Lock()
+			sub.closed = true
+			close(sub.ch)
+			sub.mu.Unlock()
+		})
+	}
+
+	return sub.ch, unsubscribe
+}
+
+// snapshot returns the current subscribers. Events are sent to the snapshot
+// without holding the bus lock, so subscribers may (un)subscribe from within
+// their handlers while a publisher waits for them.
+func (b *EventBus) snapshot() []*subscriber {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	subscribers := make([]*subscriber, 0, len(b.subscribers))
+	for _, sub := range b.subscribers {
+		subscribers = append(subscribers, sub)
+	}
+	return subscribers
+}
+
+// Publish delivers the event to all subscribers. Subscribers that are not
+// keeping up will miss the event rather than block the publisher.
+func (b *EventBus) Publish(event Event) {
+	for _, sub := range b.snapshot() {
+		sub.send(context.Background(), event, false)
+	}
+}
+
+// PublishWait delivers the event to all subscribers, waiting for slow
+// subscribers to make room instead of dropping the event. Subscribers that
+// unsubscribe in the meantime are skipped. It returns early once ctx is done.
+func (b *EventBus) PublishWait(ctx context.Context, event Event) error {
+	for _, sub := range b.snapshot() {
+		if err := sub.send(ctx, event, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Events returns the event bus of the client. Push messages received from
+// Aqara should be published here so that helpers like WaitForState can react
+// to them without polling.
+func (a *AqaraClient) Events() *EventBus {
+	return a.events
+}
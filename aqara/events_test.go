@@ -0,0 +1,56 @@
+package aqara
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPublishWaitUnsubscribe(t *testing.T) {
+	bus := NewEventBus()
+	_, unsubscribe := bus.Subscribe(0)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		unsubscribe()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := bus.PublishWait(ctx, Event{SubjectID: "lumi.1"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestPublishWaitContext(t *testing.T) {
+	bus := NewEventBus()
+	_, unsubscribe := bus.Subscribe(0)
+	defer unsubscribe()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := bus.PublishWait(ctx, Event{SubjectID: "lumi.1"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, wanted %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestPublishDropsForSlowSubscriber(t *testing.T) {
+	bus := NewEventBus()
+	events, unsubscribe := bus.Subscribe(1)
+	defer unsubscribe()
+
+	bus.Publish(Event{Value: "1"})
+	bus.Publish(Event{Value: "2"})
+
+	if event := <-events; event.Value != "1" {
+		t.Errorf("got value %q, wanted %q", event.Value, "1")
+	}
+	select {
+	case event := <-events:
+		t.Errorf("got unexpected event %+v", event)
+	default:
+	}
+}
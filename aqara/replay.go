@@ -0,0 +1,78 @@
+package aqara
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ReplayEvents reads JSONL encoded events from r and publishes them on bus.
+// Each line holds either a single Event or an Aqara push message with the
+// events in its data field. The original spacing between events is kept,
+// divided by speed, so a speed of 10 replays ten times faster than recorded.
+// Events are never dropped: slow subscribers hold up the replay instead.
+// It returns the number of published events.
+func ReplayEvents(ctx context.Context, r io.Reader, bus *EventBus, speed float64) (int, error) {
+	if speed <= 0 {
+		return 0, fmt.Errorf("invalid replay speed: %v", speed)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var (
+		published int
+		lineNum   int
+		previous  int64
+	)
+
+	for scanner.Scan() {
+		lineNum++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var line struct {
+			Event
+			Data []Event `json:"data"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return published, fmt.Errorf("failed to unmarshal line %v: %w", lineNum, err)
+		}
+
+		events := line.Data
+		if len(events) == 0 && line.SubjectID != "" {
+			events = []Event{line.Event}
+		}
+
+		for _, event := range events {
+			timestamp, err := strconv.ParseInt(event.Time, 10, 64)
+			if err == nil {
+				if previous != 0 && timestamp > previous {
+					delay := time.Duration(float64(time.Duration(timestamp-previous)*time.Millisecond) / speed)
+					select {
+					case <-ctx.Done():
+						return published, ctx.Err()
+					case <-time.After(delay):
+					}
+				}
+				previous = timestamp
+			}
+
+			if err := bus.PublishWait(ctx, event); err != nil {
+				return published, err
+			}
+			published++
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return published, fmt.Errorf("failed to read events: %w", err)
+	}
+
+	return published, nil
+}
@@ -0,0 +1,127 @@
+package aqara
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplayEvents(t *testing.T) {
+	input := strings.Join([]string{
+		`{"subjectId":"lumi.1","resourceId":"0.1.85","value":"1","time":"1618914078000"}`,
+		``,
+		`{"msgType":"resource_report","data":[{"subjectId":"lumi.2","resourceId":"0.2.85","value":"0","time":"1618914078500"},{"subjectId":"lumi.1","resourceId":"0.1.85","value":"0","time":"1618914079000"}]}`,
+	}, "\n")
+
+	bus := NewEventBus()
+	events, unsubscribe := bus.Subscribe(16)
+	defer unsubscribe()
+
+	start := time.Now()
+	published, err := ReplayEvents(context.Background(), strings.NewReader(input), bus, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Errorf("replay took %v, wanted at least %v", elapsed, 10*time.Millisecond)
+	}
+	if published != 3 {
+		t.Fatalf("got %v published events, wanted %v", published, 3)
+	}
+
+	want := []string{"lumi.1", "lumi.2", "lumi.1"}
+	for i, subjectID := range want {
+		event := <-events
+		if event.SubjectID != subjectID {
+			t.Errorf("event %v: got subject %q, wanted %q", i, event.SubjectID, subjectID)
+		}
+	}
+}
+
+func TestReplayEventsInvalid(t *testing.T) {
+	bus := NewEventBus()
+
+	if _, err := ReplayEvents(context.Background(), strings.NewReader(""), bus, 0); err == nil {
+		t.Errorf("expected error for speed 0")
+	}
+
+	published, err := ReplayEvents(context.Background(), strings.NewReader("{\"subjectId\":\"lumi.1\"}\nnot json\n"), bus, 1)
+	if err == nil {
+		t.Errorf("expected error for malformed line")
+	}
+	if published != 1 {
+		t.Errorf("got %v published events, wanted %v", published, 1)
+	}
+}
+
+func TestReplayEventsSlowSubscriber(t *testing.T) {
+	const count = 20
+
+	var b strings.Builder
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&b, "{\"subjectId\":\"lumi.1\",\"resourceId\":\"0.1.85\",\"value\":\"%d\"}\n", i)
+	}
+
+	bus := NewEventBus()
+	events, unsubscribe := bus.Subscribe(1)
+
+	received := make(chan int)
+	go func() {
+		n := 0
+		for range events {
+			time.Sleep(time.Millisecond)
+			n++
+		}
+		received <- n
+	}()
+
+	published, err := ReplayEvents(context.Background(), strings.NewReader(b.String()), bus, 1)
+	unsubscribe()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n := <-received; n != count || published != count {
+		t.Errorf("got %v published and %v received events, wanted %v", published, n, count)
+	}
+}
+
+func TestReplayEventsSubscribingHandler(t *testing.T) {
+	const count = 50
+
+	var b strings.Builder
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&b, "{\"subjectId\":\"lumi.1\",\"resourceId\":\"0.1.85\",\"value\":\"%d\"}\n", i)
+	}
+
+	bus := NewEventBus()
+	events, unsubscribe := bus.Subscribe(1)
+
+	// The handler subscribes to the same bus for every event, like a rule
+	// calling WaitForState would, while the replay waits for it.
+	received := make(chan int)
+	go func() {
+		n := 0
+		for range events {
+			_, done := bus.Subscribe(16)
+			done()
+			n++
+		}
+		received <- n
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	published, err := ReplayEvents(ctx, strings.NewReader(b.String()), bus, 1)
+	unsubscribe()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n := <-received; n != count || published != count {
+		t.Errorf("got %v published and %v received events, wanted %v", published, n, count)
+	}
+}
@@ -0,0 +1,199 @@
+package aqara
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const defaultPollInterval = 5 * time.Second
+
+type ResourceValue struct {
+	SubjectID  string `json:"subjectId"`
+	ResourceID string `json:"resourceId"`
+	Value      string `json:"value"`
+	TimeStamp  int64  `json:"timeStamp"`
+}
+
+// ResourceAccess is the permission bitmask of a resource.
+type ResourceAccess int
+
+const (
+	AccessRead   ResourceAccess = 1 << iota // value can be queried
+	AccessWrite                             // value can be written
+	AccessReport                            // value changes are reported
+)
+
+// String returns the permissions as readable flags, e.g. "read/write/report".
+func (r ResourceAccess) String() string {
+	var flags []string
+	if r&AccessRead != 0 {
+		flags = append(flags, "read")
+	}
+	if r&AccessWrite != 0 {
+		flags = append(flags, "write")
+	}
+	if r&AccessReport != 0 {
+		flags = append(flags, "report")
+	}
+	if len(flags) == 0 {
+		return "-"
+	}
+	return strings.Join(flags, "/")
+}
+
+// ResourceUnit is the numeric unit code of a resource value, where 0 means
+// the value has no unit.
+type ResourceUnit int
+
+// String returns "-" for values without unit and "unit <n>" otherwise, as
+// the mapping of Aqara unit codes to symbols is not part of the API.
+func (u ResourceUnit) String() string {
+	if u == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("unit %d", int(u))
+}
+
+type ResourceInfo struct {
+	Model        string         `json:"model"`
+	ResourceID   string         `json:"resourceId"`
+	Name         string         `json:"name"`
+	Description  string         `json:"description"`
+	Unit         ResourceUnit   `json:"unit"`
+	Access       ResourceAccess `json:"access"`
+	Enums        string         `json:"enums"`
+	MinValue     int            `json:"minValue"`
+	MaxValue     int            `json:"maxValue"`
+	DefaultValue string         `json:"defaultValue"`
+}
+
+// QueryResourceInfo retrieves the resource definitions of a device model.
+// If resourceID is empty, all resources of the model are returned.
+func (a *AqaraClient) QueryResourceInfo(ctx context.Context, model, resourceID string) ([]ResourceInfo, error) {
+	type Data struct {
+		Model      string `json:"model"`
+		ResourceID string `json:"resourceId,omitempty"`
+	}
+
+	request := AqaraRequest{
+		Intent: "query.resource.info",
+		Data: Data{
+			Model:      model,
+			ResourceID: resourceID,
+		},
+	}
+
+	response := AqaraResponse{}
+
+	if err := a.apiCall(ctx, request, &response, true); err != nil {
+		return nil, err
+	}
+
+	var result []ResourceInfo
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal result: %w", err)
+	}
+
+	return result, nil
+}
+
+// QueryResourceValues retrieves the current values of the given resources of a device.
+// If no resource IDs are provided, all resources of the device are returned.
+func (a *AqaraClient) QueryResourceValues(ctx context.Context, did string, resourceIDs ...string) ([]ResourceValue, error) {
+	return a.QueryResourceValuesOf(ctx, []string{did}, resourceIDs...)
+}
+
+// QueryResourceValuesOf retrieves the current values of the given resources of
+// several devices in a single call. If no resource IDs are provided, all
+// resources of the devices are returned.
+func (a *AqaraClient) QueryResourceValuesOf(ctx context.Context, dids []string, resourceIDs ...string) ([]ResourceValue, error) {
+	type Resource struct {
+		SubjectID   string   `json:"subjectId"`
+		ResourceIDs []string `json:"resourceIds"`
+	}
+
+	type Data struct {
+		Resources []Resource `json:"resources"`
+	}
+
+	if resourceIDs == nil {
+		resourceIDs = []string{}
+	}
+
+	resources := make([]Resource, 0, len(dids))
+	for _, did := range dids {
+		resources = append(resources, Resource{SubjectID: did, ResourceIDs: resourceIDs})
+	}
+
+	request := AqaraRequest{
+		Intent: "query.resource.value",
+		Data: Data{
+			Resources: resources,
+		},
+	}
+
+	response := AqaraResponse{}
+
+	if err := a.apiCall(ctx, request, &response, true); err != nil {
+		return nil, err
+	}
+
+	var result []ResourceValue
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal result: %w", err)
+	}
+
+	return result, nil
+}
+
+// WaitForState blocks until the value of a device resource satisfies predicate
+// and returns that value. Events published on the client's event bus are
+// evaluated as they arrive; the resource is additionally polled as a fallback
+// in case no events are delivered. The wait ends with an error once ctx is done.
+func (a *AqaraClient) WaitForState(ctx context.Context, did, resourceID string, predicate func(value string) bool) (string, error) {
+	events, unsubscribe := a.events.Subscribe(16)
+	defer unsubscribe()
+
+	ticker := time.NewTicker(a.pollInterval)
+	defer ticker.Stop()
+
+	poll := func() (string, bool) {
+		values, err := a.QueryResourceValues(ctx, did, resourceID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", false
+			}
+			log.Printf("Failed to query resource %v of device %v: %v", resourceID, did, err)
+			return "", false
+		}
+		for _, v := range values {
+			if v.SubjectID == did && v.ResourceID == resourceID && predicate(v.Value) {
+				return v.Value, true
+			}
+		}
+		return "", false
+	}
+
+	if value, ok := poll(); ok {
+		return value, nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("waiting for resource %v of device %v: %w", resourceID, did, ctx.Err())
+		case event := <-events:
+			if event.SubjectID == did && event.ResourceID == resourceID && predicate(event.Value) {
+				return event.Value, nil
+			}
+		case <-ticker.C:
+			if value, ok := poll(); ok {
+				return value, nil
+			}
+		}
+	}
+}
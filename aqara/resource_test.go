@@ -0,0 +1,170 @@
+package aqara
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWaitForStatePolling(t *testing.T) {
+	var calls atomic.Int32
+	aqaraClient := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		value := "0"
+		if calls.Add(1) >= 3 {
+			value = "1"
+		}
+		fmt.Fprintf(w, `{"code":0,"result":[{"subjectId":"lumi.1","resourceId":"0.1.85","value":%q,"timeStamp":1}]}`, value)
+	})
+	aqaraClient.pollInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	value, err := aqaraClient.WaitForState(ctx, "lumi.1", "0.1.85", func(v string) bool { return v == "1" })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "1" {
+		t.Errorf("got value %q, wanted value %q", value, "1")
+	}
+}
+
+func TestWaitForStateEvent(t *testing.T) {
+	aqaraClient := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"code":0,"result":[{"subjectId":"lumi.1","resourceId":"0.1.85","value":"0","timeStamp":1}]}`)
+	})
+	aqaraClient.pollInterval = time.Hour
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		aqaraClient.Events().Publish(Event{SubjectID: "lumi.2", ResourceID: "0.1.85", Value: "1"})
+		aqaraClient.Events().Publish(Event{SubjectID: "lumi.1", ResourceID: "0.1.85", Value: "1"})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	value, err := aqaraClient.WaitForState(ctx, "lumi.1", "0.1.85", func(v string) bool { return v == "1" })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "1" {
+		t.Errorf("got value %q, wanted value %q", value, "1")
+	}
+}
+
+func TestWaitForStateTimeout(t *testing.T) {
+	aqaraClient := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"code":0,"result":[{"subjectId":"lumi.1","resourceId":"0.1.85","value":"0","timeStamp":1}]}`)
+	})
+	aqaraClient.pollInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := aqaraClient.WaitForState(ctx, "lumi.1", "0.1.85", func(v string) bool { return v == "1" })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, wanted %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestWaitForStateHangingRequest(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	aqaraClient := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := aqaraClient.WaitForState(ctx, "lumi.1", "0.1.85", func(v string) bool { return v == "1" })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, wanted %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("WaitForState returned after %v despite context deadline", elapsed)
+	}
+}
+
+func TestQueryResourceInfo(t *testing.T) {
+	aqaraClient := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Intent string `json:"intent"`
+			Data   struct {
+				Model string `json:"model"`
+			} `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			return
+		}
+		if request.Intent != "query.resource.info" || request.Data.Model != "lumi.plug.v1" {
+			t.Errorf("got intent %q for model %q", request.Intent, request.Data.Model)
+		}
+		fmt.Fprint(w, `{"code":0,"result":[
+			{"resourceId":"4.1.85","name":"plug_status","unit":0,"access":7,"enums":"0:off,1:on"},
+			{"resourceId":"0.12.85","name":"load_power","unit":12,"access":5,"minValue":0,"maxValue":2300}
+		]}`)
+	})
+
+	infos, err := aqaraClient.QueryResourceInfo(context.Background(), "lumi.plug.v1", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("got %v resources, wanted %v", len(infos), 2)
+	}
+	if got := infos[0].Access.String(); got != "read/write/report" {
+		t.Errorf("got access %q, wanted %q", got, "read/write/report")
+	}
+	if got := infos[0].Unit.String(); got != "-" {
+		t.Errorf("got unit %q, wanted %q", got, "-")
+	}
+	if got := infos[1].Unit.String(); got != "unit 12" {
+		t.Errorf("got unit %q, wanted %q", got, "unit 12")
+	}
+	if infos[1].MaxValue != 2300 {
+		t.Errorf("got max value %v, wanted %v", infos[1].MaxValue, 2300)
+	}
+}
+
+func TestResourceAccessString(t *testing.T) {
+	tests := map[ResourceAccess]string{
+		0:                                       "-",
+		AccessRead:                              "read",
+		AccessRead | AccessReport:               "read/report",
+		AccessWrite:                             "write",
+		AccessRead | AccessWrite:                "read/write",
+		AccessRead | AccessWrite | AccessReport: "read/write/report",
+	}
+
+	for access, want := range tests {
+		if got := access.String(); got != want {
+			t.Errorf("ResourceAccess(%d).String() = %q, wanted %q", int(access), got, want)
+		}
+	}
+}
+
+func TestResourceUnitString(t *testing.T) {
+	tests := map[ResourceUnit]string{
+		0:  "-",
+		12: "unit 12",
+	}
+
+	for unit, want := range tests {
+		if got := unit.String(); got != want {
+			t.Errorf("ResourceUnit(%d).String() = %q, wanted %q", int(unit), got, want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package aqara
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// codeTooManyRequests is the Aqara result code for an exceeded request quota.
+const codeTooManyRequests = 429
+
+const (
+	minThrottleCooldown = 5 * time.Second
+	maxThrottleCooldown = 5 * time.Minute
+)
+
+// ThrottledError is returned when the Aqara API rejected a request because of
+// rate limiting. RetryAfter is the time the client pauses before sending the
+// next request.
+type ThrottledError struct {
+	StatusCode int
+	Code       int
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	if e.Code != 0 {
+		return fmt.Sprintf("request against Aqara API throttled with code %v, retry after %v", e.Code, e.RetryAfter)
+	}
+	return fmt.Sprintf("request against Aqara API throttled with status %v, retry after %v", e.StatusCode, e.RetryAfter)
+}
+
+// throttleState keeps track of the cool-down imposed by the Aqara API.
+type throttleState struct {
+	mu      sync.Mutex
+	until   time.Time
+	strikes int
+}
+
+// waitForThrottle blocks until a previously imposed cool-down has passed or ctx is done.
+func (a *AqaraClient) waitForThrottle(ctx context.Context) error {
+	a.throttling.mu.Lock()
+	wait := time.Until(a.throttling.until)
+	a.throttling.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	log.Printf("Request throttled, waiting %v", wait)
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// throttle pauses all further requests of the client and returns the
+// corresponding ThrottledError. The cool-down is taken from the Retry-After
+// header if present and otherwise doubles with every consecutive throttle.
+func (a *AqaraClient) throttle(statusCode, code int, retryAfter string) error {
+	a.throttling.mu.Lock()
+	defer a.throttling.mu.Unlock()
+
+	a.throttling.strikes++
+
+	wait, ok := parseRetryAfter(retryAfter, time.Now())
+	if !ok {
+		wait = minThrottleCooldown << (a.throttling.strikes - 1)
+		if wait <= 0 || wait > maxThrottleCooldown {
+			wait = maxThrottleCooldown
+		}
+	}
+
+	if until := time.Now().Add(wait); until.After(a.throttling.until) {
+		a.throttling.until = until
+	}
+
+	return &ThrottledError{
+		StatusCode: statusCode,
+		Code:       code,
+		RetryAfter: wait,
+	}
+}
+
+// resetThrottle resets the computed cool-down after a successful request.
+func (a *AqaraClient) resetThrottle() {
+	a.throttling.mu.Lock()
+	a.throttling.strikes = 0
+	a.throttling.mu.Unlock()
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either a
+// number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait := date.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return 0, false
+}
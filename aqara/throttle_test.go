@@ -0,0 +1,86 @@
+package aqara
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2021, 4, 20, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"-1", 0, false},
+		{"Tue, 20 Apr 2021 10:00:30 GMT", 30 * time.Second, true},
+		{"Tue, 20 Apr 2021 09:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, test := range tests {
+		got, ok := parseRetryAfter(test.value, now)
+		if got != test.want || ok != test.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, wanted %v, %v", test.value, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestThrottledStatus(t *testing.T) {
+	aqaraClient := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	_, err := aqaraClient.QueryResourceValues(context.Background(), "lumi.1")
+
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("got error %v, wanted ThrottledError", err)
+	}
+	if throttled.StatusCode != http.StatusTooManyRequests || throttled.RetryAfter != 0 {
+		t.Errorf("got %+v, wanted status %v without delay", throttled, http.StatusTooManyRequests)
+	}
+}
+
+func TestThrottledCode(t *testing.T) {
+	aqaraClient := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"code":%v,"message":"Too many requests"}`, codeTooManyRequests)
+	})
+
+	_, err := aqaraClient.QueryResourceValues(context.Background(), "lumi.1")
+
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("got error %v, wanted ThrottledError", err)
+	}
+	if throttled.Code != codeTooManyRequests || throttled.RetryAfter != minThrottleCooldown {
+		t.Errorf("got %+v, wanted code %v with delay %v", throttled, codeTooManyRequests, minThrottleCooldown)
+	}
+	if wait := time.Until(aqaraClient.throttling.until); wait <= 0 || wait > minThrottleCooldown {
+		t.Errorf("got cool-down of %v, wanted up to %v", wait, minThrottleCooldown)
+	}
+}
+
+func TestWaitForThrottleContext(t *testing.T) {
+	aqaraClient := New(ServerRegionEurope, "appid", "keyid", "appkey", "account", false)
+	aqaraClient.throttling.until = time.Now().Add(time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := aqaraClient.waitForThrottle(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, wanted %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("waited %v despite context deadline", elapsed)
+	}
+}
@@ -0,0 +1,321 @@
+package aqara
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+)
+
+// ResourceLinkQuality is the resource reporting the Zigbee link quality of a sub-device.
+const ResourceLinkQuality = "8.0.2007"
+
+// linkQualityBatchSize is the number of devices whose link quality is queried per call.
+const linkQualityBatchSize = 50
+
+type Position struct {
+	PositionID       string `json:"positionId"`
+	PositionName     string `json:"positionName"`
+	Description      string `json:"description"`
+	CreateTime       string `json:"createTime"`
+	ParentPositionID string `json:"parentPositionId"`
+}
+
+// Topology holds the position tree of an account with its devices and the
+// link quality of sub-devices, keyed by device ID.
+type Topology struct {
+	Positions   []Position
+	Devices     []Device
+	LinkQuality map[string]string
+}
+
+// QueryPositions returns the child positions of the given position.
+// If parentPositionID is empty, the top level positions are returned.
+func (a *AqaraClient) QueryPositions(ctx context.Context, parentPositionID string) ([]Position, error) {
+	type Data struct {
+		ParentPositionID string `json:"parentPositionId"`
+		PageNum          int    `json:"pageNum"`
+		PageSize         int    `json:"pageSize"`
+	}
+
+	type Result struct {
+		Data       []Position `json:"data"`
+		TotalCount int        `json:"totalCount"`
+	}
+
+	var positions []Position
+	for pageNum := 1; ; pageNum++ {
+		request := AqaraRequest{
+			Intent: "query.position.info",
+			Data: Data{
+				ParentPositionID: parentPositionID,
+				PageNum:          pageNum,
+				PageSize:         pageSize,
+			},
+		}
+
+		response := AqaraResponse{}
+
+		if err := a.apiCall(ctx, request, &response, true); err != nil {
+			return nil, err
+		}
+
+		var result Result
+		if err := json.Unmarshal(response.Result, &result); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal result: %w", err)
+		}
+
+		for _, position := range result.Data {
+			position.ParentPositionID = parentPositionID
+			positions = append(positions, position)
+		}
+		if len(result.Data) == 0 || len(positions) >= result.TotalCount {
+			return positions, nil
+		}
+	}
+}
+
+// QueryTopology retrieves the complete position tree, all devices and the
+// link quality of all sub-devices. Link quality is queried in batches of
+// linkQualityBatchSize devices; failed batches are logged and skipped.
+func (a *AqaraClient) QueryTopology(ctx context.Context) (*Topology, error) {
+	topology := &Topology{
+		LinkQuality: make(map[string]string),
+	}
+
+	parents := []string{""}
+	for len(parents) > 0 {
+		positions, err := a.QueryPositions(ctx, parents[0])
+		if err != nil {
+			return nil, err
+		}
+		parents = parents[1:]
+
+		for _, position := range positions {
+			topology.Positions = append(topology.Positions, position)
+			parents = append(parents, position.PositionID)
+		}
+	}
+
+	devices, err := a.QueryDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	topology.Devices = devices
+
+	var subDevices []string
+	for _, device := range devices {
+		if device.ParentDID != "" {
+			subDevices = append(subDevices, device.DID)
+		}
+	}
+
+	for len(subDevices) > 0 {
+		batch := subDevices
+		if len(batch) > linkQualityBatchSize {
+			batch = batch[:linkQualityBatchSize]
+		}
+		subDevices = subDevices[len(batch):]
+
+		values, err := a.QueryResourceValuesOf(ctx, batch, ResourceLinkQuality)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("Failed to query link quality of %v devices: %v", len(batch), err)
+			continue
+		}
+		for _, v := range values {
+			if v.ResourceID == ResourceLinkQuality {
+				topology.LinkQuality[v.SubjectID] = v.Value
+			}
+		}
+	}
+
+	return topology, nil
+}
+
+// WriteDOT renders the topology as Graphviz DOT. Positions become nested
+// clusters and sub-devices are connected to their hub, labeled with the link quality.
+func (t *Topology) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	ids := t.nodeIDs()
+
+	b.WriteString("digraph aqara {\n")
+	b.WriteString("\tnode [shape=box];\n")
+
+	var writePosition func(parentID, indent string)
+	writePosition = func(parentID, indent string) {
+		for _, device := range t.devicesIn(parentID) {
+			fmt.Fprintf(&b, "%s%s [label=%s];\n", indent, ids[device.DID], dotQuote(deviceLabel(device)))
+		}
+		for _, position := range t.childPositions(parentID) {
+			fmt.Fprintf(&b, "%ssubgraph cluster_%s {\n", indent, ids[position.PositionID])
+			fmt.Fprintf(&b, "%s\tlabel=%s;\n", indent, dotQuote(position.PositionName))
+			writePosition(position.PositionID, indent+"\t")
+			fmt.Fprintf(&b, "%s}\n", indent)
+		}
+	}
+	writePosition("", "\t")
+
+	for _, device := range t.subDevices() {
+		fmt.Fprintf(&b, "\t%s -> %s", ids[device.ParentDID], ids[device.DID])
+		if lqi, ok := t.LinkQuality[device.DID]; ok {
+			fmt.Fprintf(&b, " [label=%s]", dotQuote("LQI "+lqi))
+		}
+		b.WriteString(";\n")
+	}
+
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid renders the topology as Mermaid flowchart. Positions become
+// nested subgraphs and sub-devices are connected to their hub, labeled with the link quality.
+func (t *Topology) WriteMermaid(w io.Writer) error {
+	var b strings.Builder
+	ids := t.nodeIDs()
+
+	b.WriteString("flowchart TD\n")
+
+	var writePosition func(parentID, indent string)
+	writePosition = func(parentID, indent string) {
+		for _, device := range t.devicesIn(parentID) {
+			fmt.Fprintf(&b, "%s%s[%s]\n", indent, ids[device.DID], mermaidQuote(deviceLabel(device)))
+		}
+		for _, position := range t.childPositions(parentID) {
+			fmt.Fprintf(&b, "%ssubgraph %s[%s]\n", indent, ids[position.PositionID], mermaidQuote(position.PositionName))
+			writePosition(position.PositionID, indent+"    ")
+			fmt.Fprintf(&b, "%send\n", indent)
+		}
+	}
+	writePosition("", "    ")
+
+	for _, device := range t.subDevices() {
+		if lqi, ok := t.LinkQuality[device.DID]; ok {
+			fmt.Fprintf(&b, "    %s -->|%s| %s\n", ids[device.ParentDID], mermaidQuote("LQI "+lqi), ids[device.DID])
+		} else {
+			fmt.Fprintf(&b, "    %s --> %s\n", ids[device.ParentDID], ids[device.DID])
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// nodeIDs assigns stable, diagram safe identifiers to all positions and devices.
+func (t *Topology) nodeIDs() map[string]string {
+	ids := make(map[string]string, len(t.Positions)+len(t.Devices))
+	for i, position := range t.sortedPositions() {
+		ids[position.PositionID] = fmt.Sprintf("p%d", i)
+	}
+	for i, device := range t.sortedDevices() {
+		ids[device.DID] = fmt.Sprintf("d%d", i)
+	}
+	return ids
+}
+
+// childPositions returns the positions below parentID sorted by name.
+// Positions whose parent is unknown are treated as top level positions.
+func (t *Topology) childPositions(parentID string) []Position {
+	known := make(map[string]bool, len(t.Positions))
+	for _, position := range t.Positions {
+		known[position.PositionID] = true
+	}
+
+	var children []Position
+	for _, position := range t.sortedPositions() {
+		parent := position.ParentPositionID
+		if !known[parent] {
+			parent = ""
+		}
+		if parent == parentID {
+			children = append(children, position)
+		}
+	}
+	return children
+}
+
+// devicesIn returns the devices placed in the given position sorted by name.
+// Devices in unknown positions are placed at the top level.
+func (t *Topology) devicesIn(positionID string) []Device {
+	known := make(map[string]bool, len(t.Positions))
+	for _, position := range t.Positions {
+		known[position.PositionID] = true
+	}
+
+	var devices []Device
+	for _, device := range t.sortedDevices() {
+		position := device.PositionID
+		if !known[position] {
+			position = ""
+		}
+		if position == positionID {
+			devices = append(devices, device)
+		}
+	}
+	return devices
+}
+
+// subDevices returns all devices whose parent device is part of the topology.
+func (t *Topology) subDevices() []Device {
+	known := make(map[string]bool, len(t.Devices))
+	for _, device := range t.Devices {
+		known[device.DID] = true
+	}
+
+	var devices []Device
+	for _, device := range t.sortedDevices() {
+		if device.ParentDID != "" && known[device.ParentDID] {
+			devices = append(devices, device)
+		}
+	}
+	return devices
+}
+
+func (t *Topology) sortedPositions() []Position {
+	positions := append([]Position(nil), t.Positions...)
+	sort.SliceStable(positions, func(i, j int) bool {
+		if positions[i].PositionName != positions[j].PositionName {
+			return positions[i].PositionName < positions[j].PositionName
+		}
+		return positions[i].PositionID < positions[j].PositionID
+	})
+	return positions
+}
+
+func (t *Topology) sortedDevices() []Device {
+	devices := append([]Device(nil), t.Devices...)
+	sort.SliceStable(devices, func(i, j int) bool {
+		if devices[i].DeviceName != devices[j].DeviceName {
+			return devices[i].DeviceName < devices[j].DeviceName
+		}
+		return devices[i].DID < devices[j].DID
+	})
+	return devices
+}
+
+// deviceLabel returns the label of a device node.
+func deviceLabel(device Device) string {
+	return fmt.Sprintf("%s\n%s", device.DeviceName, device.Model)
+}
+
+// dotQuote returns s as quoted DOT string.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// mermaidQuote returns s as quoted Mermaid label.
+func mermaidQuote(s string) string {
+	s = strings.ReplaceAll(s, `"`, "#quot;")
+	s = strings.ReplaceAll(s, "\n", "<br/>")
+	return `"` + s + `"`
+}
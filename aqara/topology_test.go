@@ -0,0 +1,207 @@
+package aqara
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testTopology() *Topology {
+	return &Topology{
+		Positions: []Position{
+			{PositionID: "real1.home", PositionName: "Home"},
+			{PositionID: "real1.living", PositionName: "Living \"Room\"", ParentPositionID: "real1.home"},
+		},
+		Devices: []Device{
+			{DID: "lumi.hub", DeviceName: "Hub", Model: "lumi.gateway.aqhm01", PositionID: "real1.home"},
+			{DID: "lumi.sensor", DeviceName: "Sensor", Model: "lumi.sensor_magnet.v2", PositionID: "real1.living", ParentDID: "lumi.hub"},
+			{DID: "lumi.plug", DeviceName: "Plug", Model: "lumi.plug.v1", PositionID: "real1.living", ParentDID: "lumi.hub"},
+		},
+		LinkQuality: map[string]string{"lumi.sensor": "120"},
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	want := strings.Join([]string{
+		`digraph aqara {`,
+		"\tnode [shape=box];",
+		"\tsubgraph cluster_p0 {",
+		"\t\tlabel=\"Home\";",
+		"\t\td0 [label=\"Hub\\nlumi.gateway.aqhm01\"];",
+		"\t\tsubgraph cluster_p1 {",
+		"\t\t\tlabel=\"Living \\\"Room\\\"\";",
+		"\t\t\td1 [label=\"Plug\\nlumi.plug.v1\"];",
+		"\t\t\td2 [label=\"Sensor\\nlumi.sensor_magnet.v2\"];",
+		"\t\t}",
+		"\t}",
+		"\td0 -> d1;",
+		"\td0 -> d2 [label=\"LQI 120\"];",
+		"}",
+		"",
+	}, "\n")
+
+	var b strings.Builder
+	if err := testTopology().WriteDOT(&b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwanted:\n%s", got, want)
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+	want := strings.Join([]string{
+		`flowchart TD`,
+		`    subgraph p0["Home"]`,
+		`        d0["Hub<br/>lumi.gateway.aqhm01"]`,
+		`        subgraph p1["Living #quot;Room#quot;"]`,
+		`            d1["Plug<br/>lumi.plug.v1"]`,
+		`            d2["Sensor<br/>lumi.sensor_magnet.v2"]`,
+		`        end`,
+		`    end`,
+		`    d0 --> d1`,
+		`    d0 -->|"LQI 120"| d2`,
+		``,
+	}, "\n")
+
+	var b strings.Builder
+	if err := testTopology().WriteMermaid(&b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwanted:\n%s", got, want)
+	}
+}
+
+func TestQueryTopology(t *testing.T) {
+	const subDevices = 60
+
+	var devices []string
+	devices = append(devices, `{"did":"lumi.hub","deviceName":"Hub","positionId":"real1.home"}`)
+	for i := 0; i < subDevices; i++ {
+		devices = append(devices, fmt.Sprintf(`{"did":"lumi.%d","parentDid":"lumi.hub","positionId":"real1.living"}`, i))
+	}
+
+	children := map[string]string{
+		"":             `[{"positionId":"real1.home","positionName":"Home"}]`,
+		"real1.home":   `[{"positionId":"real1.living","positionName":"Living"},{"positionId":"real1.kitchen","positionName":"Kitchen"}]`,
+		"real1.living": `[]`,
+	}
+
+	var resourceCalls []int
+	aqaraClient := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Intent string          `json:"intent"`
+			Data   json.RawMessage `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			return
+		}
+
+		switch request.Intent {
+		case "query.position.info":
+			var data struct {
+				ParentPositionID string `json:"parentPositionId"`
+			}
+			json.Unmarshal(request.Data, &data)
+			positions, ok := children[data.ParentPositionID]
+			if !ok {
+				positions = `[]`
+			}
+			var count []json.RawMessage
+			json.Unmarshal([]byte(positions), &count)
+			fmt.Fprintf(w, `{"code":0,"result":{"data":%s,"totalCount":%d}}`, positions, len(count))
+		case "query.device.info":
+			fmt.Fprintf(w, `{"code":0,"result":{"data":[%s],"totalCount":%d}}`, strings.Join(devices, ","), len(devices))
+		case "query.resource.value":
+			var data struct {
+				Resources []struct {
+					SubjectID   string   `json:"subjectId"`
+					ResourceIDs []string `json:"resourceIds"`
+				} `json:"resources"`
+			}
+			json.Unmarshal(request.Data, &data)
+			resourceCalls = append(resourceCalls, len(data.Resources))
+
+			var values []string
+			for _, resource := range data.Resources {
+				if len(resource.ResourceIDs) != 1 || resource.ResourceIDs[0] != ResourceLinkQuality {
+					t.Errorf("got resources %v, wanted %v", resource.ResourceIDs, ResourceLinkQuality)
+				}
+				values = append(values, fmt.Sprintf(`{"subjectId":%q,"resourceId":%q,"value":"100"}`, resource.SubjectID, ResourceLinkQuality))
+			}
+			fmt.Fprintf(w, `{"code":0,"result":[%s]}`, strings.Join(values, ","))
+		default:
+			t.Errorf("unexpected intent %q", request.Intent)
+		}
+	})
+
+	topology, err := aqaraClient.QueryTopology(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantPositions := []Position{
+		{PositionID: "real1.home", PositionName: "Home"},
+		{PositionID: "real1.living", PositionName: "Living", ParentPositionID: "real1.home"},
+		{PositionID: "real1.kitchen", PositionName: "Kitchen", ParentPositionID: "real1.home"},
+	}
+	if !reflect.DeepEqual(topology.Positions, wantPositions) {
+		t.Errorf("got positions %+v, wanted %+v", topology.Positions, wantPositions)
+	}
+
+	if len(topology.Devices) != subDevices+1 {
+		t.Errorf("got %v devices, wanted %v", len(topology.Devices), subDevices+1)
+	}
+
+	if want := []int{linkQualityBatchSize, subDevices - linkQualityBatchSize}; !reflect.DeepEqual(resourceCalls, want) {
+		t.Errorf("got link quality batches %v, wanted %v", resourceCalls, want)
+	}
+	if len(topology.LinkQuality) != subDevices || topology.LinkQuality["lumi.0"] != "100" {
+		t.Errorf("got link quality for %v devices, wanted %v", len(topology.LinkQuality), subDevices)
+	}
+	if _, ok := topology.LinkQuality["lumi.hub"]; ok {
+		t.Errorf("got link quality for hub, wanted none")
+	}
+}
+
+func TestQueryDevicesPagination(t *testing.T) {
+	const total = 250
+
+	var pages []int
+	aqaraClient := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Data struct {
+				PageNum  int `json:"pageNum"`
+				PageSize int `json:"pageSize"`
+			} `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			return
+		}
+		pages = append(pages, request.Data.PageNum)
+
+		var devices []string
+		for i := (request.Data.PageNum - 1) * request.Data.PageSize; i < total && i < request.Data.PageNum*request.Data.PageSize; i++ {
+			devices = append(devices, fmt.Sprintf(`{"did":"lumi.%d"}`, i))
+		}
+		fmt.Fprintf(w, `{"code":0,"result":{"data":[%s],"totalCount":%d}}`, strings.Join(devices, ","), total)
+	})
+
+	devices, err := aqaraClient.QueryDevices(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(devices) != total || devices[total-1].DID != fmt.Sprintf("lumi.%d", total-1) {
+		t.Errorf("got %v devices, wanted %v", len(devices), total)
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(pages, want) {
+		t.Errorf("got pages %v, wanted %v", pages, want)
+	}
+}
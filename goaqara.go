@@ -1,24 +1,67 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 
 	"github.com/roger-dodger/goaqara/aqara"
 )
 
-var (
-	appID   = flag.String("appid", "", "Aqara App ID")
-	keyID   = flag.String("keyid", "", "Aqara Key ID")
-	appKey  = flag.String("appkey", "", "Aqara App Key")
-	region  = flag.String("region", "europe", "Aqara server region: china, usa, southkorea, russia, europe, singapore")
-	account = flag.String("account", "", "Aqara registered phone number or email address")
-	debug   = flag.Bool("debug", false, "enable debug output")
-)
-
 func main() {
-	flag.Parse()
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr, &http.Client{}))
+}
+
+// run executes the command line given by args. Only command output is
+// written to stdout, prompts and diagnostics go to stderr so that the
+// output can be piped into other tools.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer, httpClient *http.Client) int {
+	flags := flag.NewFlagSet("goaqara", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	appID := flags.String("appid", "", "Aqara App ID")
+	keyID := flags.String("keyid", "", "Aqara Key ID")
+	appKey := flags.String("appkey", "", "Aqara App Key")
+	region := flags.String("region", "europe", "Aqara server region: china, usa, southkorea, russia, europe, singapore")
+	account := flags.String("account", "", "Aqara registered phone number or email address")
+	debug := flags.Bool("debug", false, "enable debug output")
+
+	flags.Usage = func() { usage(flags) }
+	if err := flags.Parse(args); err != nil {
+		return -1
+	}
+
+	var topologyFormat string
+
+	command := flags.Arg(0)
+	switch command {
+	case "", "devices":
+	case "resources":
+		if flags.NArg() != 2 {
+			usage(flags)
+			return -1
+		}
+	case "replay":
+		if err := replay(flags.Args()[1:], stdout, stderr); err != nil {
+			fmt.Fprintf(stderr, "Failed to replay events: %v\n", err)
+			return -1
+		}
+		return 0
+	case "topology":
+		format, err := parseTopologyFlags(flags.Args()[1:], stderr)
+		if err != nil {
+			fmt.Fprintf(stderr, "Invalid topology arguments: %v\n", err)
+			return -1
+		}
+		topologyFormat = format
+	default:
+		fmt.Fprintf(stderr, "Unknown command %q\n", command)
+		usage(flags)
+		return -1
+	}
 
 	var serverRegion aqara.AqaraRegionServer
 
@@ -36,23 +79,52 @@ func main() {
 	case "singapore":
 		serverRegion = aqara.ServerRegionSingapore
 	default:
-		fmt.Println("No valid server region provided. Defaulting to 'europe'.")
+		fmt.Fprintln(stderr, "No valid server region provided. Defaulting to 'europe'.")
 		serverRegion = aqara.ServerRegionEurope
 	}
 
 	if *appID == "" || *keyID == "" || *appKey == "" || *account == "" {
-		fmt.Println("You must provide the following arguments: appid, keyid, appkey and account")
-		os.Exit(-1)
+		fmt.Fprintln(stderr, "You must provide the following arguments: appid, keyid, appkey and account")
+		return -1
 	}
 
 	aqaraClient := aqara.New(serverRegion, *appID, *keyID, *appKey, *account, *debug)
+	aqaraClient.SetHTTPClient(httpClient)
 	aqaraClient.GetAuthCode()
 
-	fmt.Print("Enter auth code sent via SMS or email: ")
+	fmt.Fprint(stderr, "Enter auth code sent via SMS or email: ")
 	var authCode string
-	fmt.Scanln(&authCode)
+	fmt.Fscanln(stdin, &authCode)
 
 	aqaraClient.GetToken(authCode)
 
-	aqaraClient.GetDevices()
+	switch command {
+	case "", "devices":
+		aqaraClient.GetDevices()
+	case "resources":
+		if err := listResources(context.Background(), stdout, aqaraClient, flags.Arg(1)); err != nil {
+			fmt.Fprintf(stderr, "Failed to list resources: %v\n", err)
+			return -1
+		}
+	case "topology":
+		if err := exportTopology(context.Background(), stdout, aqaraClient, topologyFormat); err != nil {
+			fmt.Fprintf(stderr, "Failed to export topology: %v\n", err)
+			return -1
+		}
+	}
+
+	return 0
+}
+
+func usage(flags *flag.FlagSet) {
+	fmt.Fprintf(flags.Output(), "Usage: %s [flags] [command]\n\n", flags.Name())
+	fmt.Fprintln(flags.Output(), "Commands:")
+	fmt.Fprintln(flags.Output(), "  devices               list all devices (default)")
+	fmt.Fprintln(flags.Output(), "  resources <device>    list resource definitions and current values of a device")
+	fmt.Fprintln(flags.Output(), "  replay <file> [-speed 10x] [-- handler [args...]]")
+	fmt.Fprintln(flags.Output(), "                        replay recorded JSONL events, running handler for each event")
+	fmt.Fprintln(flags.Output(), "  topology [-format dot|mermaid]")
+	fmt.Fprintln(flags.Output(), "                        export positions, hubs and sub-devices as diagram")
+	fmt.Fprintln(flags.Output(), "\nFlags:")
+	flags.PrintDefaults()
 }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/roger-dodger/goaqara/aqara"
+)
+
+// rewriteTransport redirects all requests to a local test server.
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (rt rewriteTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r.URL.Scheme = rt.target.Scheme
+	r.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// fakeAPI answers Aqara API calls with the result returned by handle for the
+// intent and data of the request.
+func fakeAPI(t *testing.T, handle func(intent string, data json.RawMessage) string) *http.Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Intent string          `json:"intent"`
+			Data   json.RawMessage `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			return
+		}
+		fmt.Fprintf(w, `{"code":0,"result":%s}`, handle(request.Intent, request.Data))
+	}))
+	t.Cleanup(server.Close)
+
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse test server url: %v", err)
+	}
+
+	return &http.Client{Transport: rewriteTransport{target: target}}
+}
+
+// newTestClient returns a client whose API calls are answered by handle.
+func newTestClient(t *testing.T, handle func(intent string, data json.RawMessage) string) *aqara.AqaraClient {
+	t.Helper()
+
+	aqaraClient := aqara.New(aqara.ServerRegionEurope, "appid", "keyid", "appkey", "account", false)
+	aqaraClient.SetHTTPClient(fakeAPI(t, handle))
+
+	return aqaraClient
+}
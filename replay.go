@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/roger-dodger/goaqara/aqara"
+)
+
+// replay feeds the events recorded in a JSONL file through an event bus with
+// their original timing, scaled by the given speed. If a handler command is
+// given after the file, it is run for every event, which allows testing
+// notification and automation scripts against recorded traffic. Otherwise
+// the events are printed. It does not require credentials.
+func replay(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	speed := flags.String("speed", "1x", "replay speed relative to the recorded timing, e.g. 10x")
+
+	// Allow flags both before and after the file name.
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 1 {
+		return errors.New("no event file provided")
+	}
+	path := flags.Arg(0)
+	if err := flags.Parse(flags.Args()[1:]); err != nil {
+		return err
+	}
+	handler := flags.Args()
+
+	factor, err := parseSpeed(*speed)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	bus := aqara.NewEventBus()
+	events, unsubscribe := bus.Subscribe(256)
+
+	var (
+		wg       sync.WaitGroup
+		failures int
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for event := range events {
+			if len(handler) == 0 {
+				fmt.Fprintf(stdout, "%v\t%v\t%v\t%v\n", event.Time, event.SubjectID, event.ResourceID, event.Value)
+				continue
+			}
+			if err := runHandler(ctx, handler, event, stdout, stderr); err != nil {
+				fmt.Fprintf(stderr, "Handler failed for event %v/%v: %v\n", event.SubjectID, event.ResourceID, err)
+				failures++
+			}
+		}
+	}()
+
+	published, err := aqara.ReplayEvents(ctx, file, bus, factor)
+	unsubscribe()
+	wg.Wait()
+
+	fmt.Fprintf(stderr, "Replayed %v events\n", published)
+
+	if err == nil && failures > 0 {
+		err = fmt.Errorf("handler failed for %v of %v events", failures, published)
+	}
+
+	return err
+}
+
+// runHandler runs the handler command for a single event. The event is passed
+// as JSON on stdin and as AQARA_* environment variables.
+func runHandler(ctx context.Context, handler []string, event aqara.Event, stdout, stderr io.Writer) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, handler[0], handler[1:]...)
+	cmd.Stdin = bytes.NewReader(append(data, '\n'))
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Env = append(os.Environ(),
+		"AQARA_SUBJECT_ID="+event.SubjectID,
+		"AQARA_RESOURCE_ID="+event.ResourceID,
+		"AQARA_VALUE="+event.Value,
+		"AQARA_TIME="+event.Time,
+	)
+
+	return cmd.Run()
+}
+
+// parseSpeed parses a replay speed like "10x", "0.5x" or "2".
+func parseSpeed(value string) (float64, error) {
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64)
+	if err != nil || speed <= 0 {
+		return 0, fmt.Errorf("invalid replay speed %q", value)
+	}
+
+	return speed, nil
+}
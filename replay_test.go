@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeEvents(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	events := strings.Join([]string{
+		`{"subjectId":"lumi.1","resourceId":"0.1.85","value":"1","time":"1618914078000"}`,
+		`{"msgType":"resource_report","data":[{"subjectId":"lumi.2","resourceId":"0.2.85","value":"0","time":"1618914078000"}]}`,
+	}, "\n")
+	if err := os.WriteFile(path, []byte(events), 0o600); err != nil {
+		t.Fatalf("failed to write events: %v", err)
+	}
+
+	return path
+}
+
+func TestReplayPrintsEvents(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"replay", writeEvents(t), "-speed", "1000x"}, nil, &stdout, &stderr, nil); code != 0 {
+		t.Fatalf("got exit code %v; stderr:\n%s", code, stderr.String())
+	}
+
+	want := "1618914078000\tlumi.1\t0.1.85\t1\n1618914078000\tlumi.2\t0.2.85\t0\n"
+	if got := stdout.String(); got != want {
+		t.Errorf("got stdout %q, wanted %q", got, want)
+	}
+}
+
+func TestReplayRunsHandler(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	handler := []string{"sh", "-c", `printf '%s/%s=%s ' "$AQARA_SUBJECT_ID" "$AQARA_RESOURCE_ID" "$AQARA_VALUE"; cat`}
+
+	var stdout, stderr bytes.Buffer
+	args := append([]string{"replay", writeEvents(t), "--"}, handler...)
+	if code := run(args, nil, &stdout, &stderr, nil); code != 0 {
+		t.Fatalf("got exit code %v; stderr:\n%s", code, stderr.String())
+	}
+
+	want := strings.Join([]string{
+		`lumi.1/0.1.85=1 {"subjectId":"lumi.1","resourceId":"0.1.85","value":"1","time":"1618914078000"}`,
+		`lumi.2/0.2.85=0 {"subjectId":"lumi.2","resourceId":"0.2.85","value":"0","time":"1618914078000"}`,
+		``,
+	}, "\n")
+	if got := stdout.String(); got != want {
+		t.Errorf("got stdout:\n%s\nwanted:\n%s", got, want)
+	}
+}
+
+func TestReplayHandlerFailure(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"replay", writeEvents(t), "--", "sh", "-c", `test "$AQARA_VALUE" = 1`}
+	if code := run(args, nil, &stdout, &stderr, nil); code == 0 {
+		t.Fatalf("got exit code 0, wanted failure")
+	}
+
+	if !strings.Contains(stderr.String(), "Handler failed for event lumi.2/0.2.85") {
+		t.Errorf("stderr %q does not report failed handler", stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "handler failed for 1 of 2 events") {
+		t.Errorf("stderr %q does not report failure count", stderr.String())
+	}
+}
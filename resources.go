@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/roger-dodger/goaqara/aqara"
+)
+
+// listResources prints the resource definitions of a device together with
+// their current values. The device can be given by device ID or name.
+func listResources(ctx context.Context, w io.Writer, aqaraClient *aqara.AqaraClient, device string) error {
+	target, err := findDevice(ctx, aqaraClient, device)
+	if err != nil {
+		return err
+	}
+
+	infos, err := aqaraClient.QueryResourceInfo(ctx, target.Model, "")
+	if err != nil {
+		return err
+	}
+
+	values, err := aqaraClient.QueryResourceValues(ctx, target.DID)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]string, len(values))
+	for _, v := range values {
+		current[v.ResourceID] = v.Value
+	}
+
+	fmt.Fprintf(w, "%v (%v, %v)\n\n", target.DeviceName, target.DID, target.Model)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tUNIT\tACCESS\tRANGE\tENUMS\tVALUE")
+	for _, info := range infos {
+		valueRange := ""
+		if info.MinValue != 0 || info.MaxValue != 0 {
+			valueRange = fmt.Sprintf("%v..%v", info.MinValue, info.MaxValue)
+		}
+		value, ok := current[info.ResourceID]
+		if !ok {
+			value = "-"
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n", info.ResourceID, info.Name, info.Unit, info.Access, valueRange, info.Enums, value)
+	}
+
+	return tw.Flush()
+}
+
+// findDevice resolves a device by device ID or, failing that, by name.
+func findDevice(ctx context.Context, aqaraClient *aqara.AqaraClient, device string) (*aqara.Device, error) {
+	devices, err := aqaraClient.QueryDevices(ctx, device)
+	var apiErr *aqara.APIError
+	if err != nil && !errors.As(err, &apiErr) {
+		return nil, err
+	}
+	for i := range devices {
+		if devices[i].DID == device {
+			return &devices[i], nil
+		}
+	}
+
+	devices, err = aqaraClient.QueryDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range devices {
+		if devices[i].DeviceName == device {
+			return &devices[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("device %q not found", device)
+}
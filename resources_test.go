@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func resourcesAPI(t *testing.T) func(intent string, data json.RawMessage) string {
+	return func(intent string, data json.RawMessage) string {
+		switch intent {
+		case "query.device.info":
+			var request struct {
+				DeviceIDs []string `json:"dids"`
+			}
+			if err := json.Unmarshal(data, &request); err != nil {
+				t.Errorf("failed to decode data: %v", err)
+			}
+			if len(request.DeviceIDs) == 1 && request.DeviceIDs[0] != "lumi.plug" {
+				return `{"data":[],"totalCount":0}`
+			}
+			return `{"data":[{"did":"lumi.plug","deviceName":"Kitchen Plug","model":"lumi.plug.v1"}],"totalCount":1}`
+		case "query.resource.info":
+			return `[
+				{"resourceId":"4.1.85","name":"plug_status","unit":0,"access":7,"enums":"0:off,1:on"},
+				{"resourceId":"0.12.85","name":"load_power","unit":12,"access":5,"minValue":0,"maxValue":2300}
+			]`
+		case "query.resource.value":
+			return `[{"subjectId":"lumi.plug","resourceId":"4.1.85","value":"1","timeStamp":1}]`
+		default:
+			t.Errorf("unexpected intent %q", intent)
+			return `null`
+		}
+	}
+}
+
+func TestListResources(t *testing.T) {
+	want := strings.Join([]string{
+		"Kitchen Plug (lumi.plug, lumi.plug.v1)",
+		"",
+		"ID       NAME         UNIT     ACCESS             RANGE    ENUMS       VALUE",
+		"4.1.85   plug_status  -        read/write/report           0:off,1:on  1",
+		"0.12.85  load_power   unit 12  read/report        0..2300              -",
+		"",
+	}, "\n")
+
+	for _, device := range []string{"lumi.plug", "Kitchen Plug"} {
+		t.Run(device, func(t *testing.T) {
+			aqaraClient := newTestClient(t, resourcesAPI(t))
+
+			var b bytes.Buffer
+			if err := listResources(context.Background(), &b, aqaraClient, device); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := b.String(); got != want {
+				t.Errorf("got:\n%s\nwanted:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestListResourcesUnknownDevice(t *testing.T) {
+	aqaraClient := newTestClient(t, resourcesAPI(t))
+
+	var b bytes.Buffer
+	err := listResources(context.Background(), &b, aqaraClient, "Garage")
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("got error %v, wanted device not found", err)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/roger-dodger/goaqara/aqara"
+)
+
+// parseTopologyFlags parses the arguments of the topology command and
+// returns the requested output format.
+func parseTopologyFlags(args []string, stderr io.Writer) (string, error) {
+	flags := flag.NewFlagSet("topology", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "dot", "output format: dot, mermaid")
+
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+	if flags.NArg() != 0 {
+		return "", fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+
+	switch *format {
+	case "dot", "mermaid":
+		return *format, nil
+	default:
+		return "", fmt.Errorf("unknown format %q", *format)
+	}
+}
+
+// exportTopology writes the home topology of the account in the given format.
+func exportTopology(ctx context.Context, w io.Writer, aqaraClient *aqara.AqaraClient, format string) error {
+	topology, err := aqaraClient.QueryTopology(ctx)
+	if err != nil {
+		return err
+	}
+
+	if format == "mermaid" {
+		return topology.WriteMermaid(w)
+	}
+
+	return topology.WriteDOT(w)
+}
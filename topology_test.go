@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func topologyAPI(t *testing.T) func(intent string, data json.RawMessage) string {
+	return func(intent string, data json.RawMessage) string {
+		switch intent {
+		case "config.auth.getAuthCode":
+			return `{}`
+		case "config.auth.getToken":
+			return `{"accessToken":"token","refreshToken":"refresh"}`
+		case "query.position.info":
+			var request struct {
+				ParentPositionID string `json:"parentPositionId"`
+			}
+			if err := json.Unmarshal(data, &request); err != nil {
+				t.Errorf("failed to decode data: %v", err)
+			}
+			if request.ParentPositionID == "" {
+				return `{"data":[{"positionId":"real1.home","positionName":"Home"}],"totalCount":1}`
+			}
+			return `{"data":[],"totalCount":0}`
+		case "query.device.info":
+			return `{"data":[
+				{"did":"lumi.hub","deviceName":"Hub","model":"lumi.gateway.aqhm01","positionId":"real1.home"},
+				{"did":"lumi.plug","deviceName":"Plug","model":"lumi.plug.v1","positionId":"real1.home","parentDid":"lumi.hub"}
+			],"totalCount":2}`
+		case "query.resource.value":
+			return `[{"subjectId":"lumi.plug","resourceId":"8.0.2007","value":"87"}]`
+		default:
+			t.Errorf("unexpected intent %q", intent)
+			return `null`
+		}
+	}
+}
+
+func TestTopologyStdoutOnlyDiagram(t *testing.T) {
+	want := strings.Join([]string{
+		"digraph aqara {",
+		"\tnode [shape=box];",
+		"\tsubgraph cluster_p0 {",
+		"\t\tlabel=\"Home\";",
+		"\t\td0 [label=\"Hub\\nlumi.gateway.aqhm01\"];",
+		"\t\td1 [label=\"Plug\\nlumi.plug.v1\"];",
+		"\t}",
+		"\td0 -> d1 [label=\"LQI 87\"];",
+		"}",
+		"",
+	}, "\n")
+
+	args := []string{"-appid", "appid", "-keyid", "keyid", "-appkey", "appkey", "-account", "account", "-region", "atlantis", "topology", "-format", "dot"}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(args, strings.NewReader("123456\n"), &stdout, &stderr, fakeAPI(t, topologyAPI(t))); code != 0 {
+		t.Fatalf("got exit code %v, wanted 0; stderr:\n%s", code, stderr.String())
+	}
+
+	if got := stdout.String(); got != want {
+		t.Errorf("got stdout:\n%s\nwanted:\n%s", got, want)
+	}
+	for _, message := range []string{"No valid server region provided", "Enter auth code"} {
+		if !strings.Contains(stderr.String(), message) {
+			t.Errorf("stderr %q does not contain %q", stderr.String(), message)
+		}
+	}
+}
+
+func TestTopologyErrorOnStderr(t *testing.T) {
+	args := []string{"-appid", "appid", "-keyid", "keyid", "-appkey", "appkey", "-account", "account", "topology", "-format", "mermaid"}
+
+	api := func(intent string, data json.RawMessage) string {
+		if intent == "query.position.info" {
+			return `"malformed"`
+		}
+		return topologyAPI(t)(intent, data)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(args, strings.NewReader("123456\n"), &stdout, &stderr, fakeAPI(t, api)); code == 0 {
+		t.Fatalf("got exit code 0, wanted failure")
+	}
+
+	if stdout.Len() != 0 {
+		t.Errorf("got stdout %q, wanted none", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "Failed to export topology") {
+		t.Errorf("stderr %q does not contain export error", stderr.String())
+	}
+}